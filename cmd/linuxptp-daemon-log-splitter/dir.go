@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nocturnalastro/linuxptp-daemon-log-splitter/pkg/logsplit"
+)
+
+// runDir splits every *.log/*.txt file under -inputdir concurrently,
+// mirroring the input tree under -outprefix (default "split") and
+// reporting per-file errors after the whole batch has run rather than
+// aborting on the first one.
+func runDir(flags cliFlags) {
+	outPrefix := flags.outPrefix
+	if outPrefix == "" {
+		outPrefix = defaultOutPrefix
+	}
+
+	results, err := logsplit.ProcessDir(flags.inputDir, logsplit.BatchOptions{
+		Parallel: flags.parallel,
+		OutPrefix: func(relPath string) string {
+			base := relPath[:len(relPath)-len(filepath.Ext(relPath))]
+			return filepath.Join(outPrefix, base)
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", r.Path, r.Err)
+			continue
+		}
+		if !r.Result.AnyRunFound() {
+			fmt.Fprintf(os.Stderr, "%s: no run tokens found, wrote %s.run_unknown.log\n", r.Path, r.OutPrefix)
+		} else {
+			fmt.Printf("%s: split into %d run(s) under %s\n", r.Path, len(r.Result.RunsFound), r.OutPrefix)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Processed %d file(s), %d failed\n", len(results), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,183 @@
+// Command linuxptp-daemon-log-splitter splits combined PTP daemon logs into per-run files.
+// It scans for tokens like "ptp4l.N.config" or "phc2sys.N.config" and writes
+// each line to the corresponding run N output. Lines without a run token are
+// treated as global and included in all run files. If no run tokens are seen,
+// a single "run_unknown" file is produced containing all lines.
+//
+// Input is read from -input (file path), -inputdir (a directory of logs
+// split in parallel), or stdin. Output filenames are prefixed by -outprefix
+// or derived from the input filename.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/nocturnalastro/linuxptp-daemon-log-splitter/pkg/logsplit"
+)
+
+const defaultOutPrefix = "split"
+
+type cliFlags struct {
+	inputFile      string
+	inputDir       string
+	outPrefix      string
+	parallel       int
+	shard          int
+	shards         int
+	manifestPath   string
+	splitByDaemon  bool
+	mode           string
+	maxCommonBytes int64
+	help           bool
+}
+
+// parseFlags defines and parses command-line flags and returns them.
+func parseFlags() cliFlags {
+	var flags cliFlags
+	flag.StringVar(&flags.inputFile, "input", "", "Input file (default: stdin)")
+	flag.StringVar(&flags.inputDir, "inputdir", "", "Input directory to walk for *.log/*.txt files, split concurrently (overrides -input)")
+	flag.StringVar(&flags.outPrefix, "outprefix", "", "Output file prefix (default: derived from input or 'split')")
+	flag.IntVar(&flags.parallel, "parallel", runtime.NumCPU(), "Number of files to split concurrently in -inputdir mode")
+	flag.IntVar(&flags.shard, "shard", 0, "This instance's shard index, in [0, shards); use with -shards to split one huge -input across cooperating processes")
+	flag.IntVar(&flags.shards, "shards", 1, "Total number of shards; 1 disables sharding")
+	flag.StringVar(&flags.manifestPath, "manifest", "", "Write a JSON manifest of per-run stats (bytes, lines, timestamp span, daemons) to this path")
+	flag.BoolVar(&flags.splitByDaemon, "split-by-daemon", false, "Also emit '<outprefix>.run_<id>.<daemon>.log' per daemon seen in each run")
+	flag.StringVar(&flags.mode, "mode", "auto", "Common-line strategy: auto, stream, buffered, or twopass (twopass requires a seekable -input)")
+	flag.Int64Var(&flags.maxCommonBytes, "max-common-bytes", 0, "In -mode buffered, bytes of common prefix kept in memory before spilling to disk (default 4MiB)")
+	flag.BoolVar(&flags.help, "h", false, "Show help")
+	flag.BoolVar(&flags.help, "help", false, "Show help")
+	flag.Parse()
+	return flags
+}
+
+// parseMode maps the -mode flag to a logsplit.Mode.
+func parseMode(mode string) (logsplit.Mode, error) {
+	switch mode {
+	case "", "auto":
+		return logsplit.ModeAuto, nil
+	case "stream":
+		return logsplit.ModeStream, nil
+	case "buffered":
+		return logsplit.ModeBuffered, nil
+	case "twopass":
+		return logsplit.ModeTwoPass, nil
+	default:
+		return 0, fmt.Errorf("unknown -mode %q (want auto, stream, buffered, or twopass)", mode)
+	}
+}
+
+// printUsage writes a brief usage message to stderr.
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "PTP Log Splitter\n")
+	fmt.Fprintf(os.Stderr, "Usage: %s [-input file | -inputdir dir] [-outprefix prefix] [-parallel N] [-shard I -shards N]\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Fprintf(os.Stderr, "Reads PTP logs and splits them into per-run files based on tokens like 'ptp4l.N.config' or 'phc2sys.N.config'.\n")
+	fmt.Fprintf(os.Stderr, "Lines without a run token are included in all run files.\n")
+	fmt.Fprintf(os.Stderr, "-inputdir walks a directory tree of *.log/*.txt files and splits each one concurrently.\n")
+	fmt.Fprintf(os.Stderr, "-shard/-shards run N instances over the same -input, each writing only the runs that hash into its shard as '<outprefix>.shard<I>.run_<N>.log'; common lines are written by every shard.\n")
+	fmt.Fprintf(os.Stderr, "-manifest writes a JSON summary of each run's bytes, lines, timestamp span, and daemons.\n")
+	fmt.Fprintf(os.Stderr, "-split-by-daemon additionally emits '<outprefix>.run_<id>.<daemon>.log' per daemon seen in each run.\n")
+	fmt.Fprintf(os.Stderr, "-mode picks how common lines are stored: stream (temp file), buffered (in-memory, -max-common-bytes cap), or twopass (no temp file, requires a seekable -input); auto picks twopass for file input and stream for stdin.\n")
+}
+
+// deriveOutPrefix derives an output prefix from an input file name by
+// stripping a trailing .log or .txt extension, falling back to
+// defaultOutPrefix when no input file is given.
+func deriveOutPrefix(inputFile string) string {
+	if inputFile == "" {
+		return defaultOutPrefix
+	}
+	base := filepath.Base(inputFile)
+	for _, ext := range []string{".log", ".txt"} {
+		if strings.HasSuffix(strings.ToLower(base), ext) {
+			return base[:len(base)-len(ext)]
+		}
+	}
+	return base
+}
+
+func main() {
+	flags := parseFlags()
+
+	if flags.help {
+		printUsage()
+		os.Exit(0)
+	}
+
+	if flags.inputDir != "" {
+		runDir(flags)
+		return
+	}
+	runSingle(flags)
+}
+
+// runSingle splits one input (a file named by -input, or stdin) into
+// per-run outputs under -outprefix.
+func runSingle(flags cliFlags) {
+	var inputReader io.Reader
+	var inName string
+	if flags.inputFile != "" {
+		f, err := os.Open(flags.inputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: cannot open input file: %v\n", err)
+			os.Exit(2)
+		}
+		defer f.Close()
+		inputReader = f
+		inName = filepath.Base(flags.inputFile)
+	} else {
+		// Hide *os.File's Seek so ModeAuto treats stdin as a stream.
+		inputReader = struct{ io.Reader }{os.Stdin}
+		inName = "stdin"
+	}
+
+	outPrefix := flags.outPrefix
+	if outPrefix == "" {
+		outPrefix = deriveOutPrefix(flags.inputFile)
+	}
+
+	if flags.shards > 1 {
+		if flags.shard < 0 || flags.shard >= flags.shards {
+			fmt.Fprintf(os.Stderr, "error: -shard must be in [0, %d)\n", flags.shards)
+			os.Exit(2)
+		}
+		outPrefix = fmt.Sprintf("%s.shard%d", outPrefix, flags.shard)
+	}
+
+	mode, err := parseMode(flags.mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+
+	splitter := logsplit.New(logsplit.Options{
+		OutPrefix:      outPrefix,
+		Shard:          flags.shard,
+		Shards:         flags.shards,
+		SplitByDaemon:  flags.splitByDaemon,
+		Mode:           mode,
+		MaxCommonBytes: flags.maxCommonBytes,
+	})
+	result, err := splitter.Process(inputReader)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+
+	if !result.AnyRunFound() && flags.shards <= 1 {
+		fmt.Fprintf(os.Stderr, "No run tokens found in %s. Wrote all lines to %s.run_unknown.log\n", inName, outPrefix)
+	}
+
+	if flags.manifestPath != "" {
+		if err := writeManifest(outPrefix, flags.manifestPath, result); err != nil {
+			fmt.Fprintf(os.Stderr, "error: writing manifest: %v\n", err)
+			os.Exit(2)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/nocturnalastro/linuxptp-daemon-log-splitter/pkg/logsplit"
+)
+
+// manifestEntry is a RunInfo plus the output filename the default FileSink
+// gives that run, since the library itself doesn't know a sink's naming
+// convention.
+type manifestEntry struct {
+	logsplit.RunInfo
+	File string `json:"file"`
+}
+
+// writeManifest marshals result's per-run stats, plus the filename each run
+// was written to under outPrefix, to path as JSON.
+func writeManifest(outPrefix, path string, result *logsplit.Result) error {
+	entries := make([]manifestEntry, 0, len(result.Runs))
+	for _, run := range result.Runs {
+		entries = append(entries, manifestEntry{
+			RunInfo: run,
+			File:    fmt.Sprintf("%s.run_%s.log", outPrefix, run.Run),
+		})
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
@@ -0,0 +1,133 @@
+package logsplit
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// logExtensions lists the file extensions DiscoverFiles treats as logs.
+var logExtensions = map[string]bool{
+	".log": true,
+	".txt": true,
+}
+
+// DiscoverFiles walks root and returns the paths of every regular file
+// whose extension is .log or .txt, in lexical order.
+func DiscoverFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if logExtensions[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking %s: %w", root, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// BatchOptions configures ProcessDir.
+type BatchOptions struct {
+	// Matcher identifies run tokens in each line; see Options.Matcher.
+	// Shared across all workers, so a custom Matcher must be safe for
+	// concurrent use.
+	Matcher TokenMatcher
+
+	// Parallel is the number of files processed concurrently. Defaults
+	// to runtime.NumCPU() when zero or negative.
+	Parallel int
+
+	// OutPrefix derives the OutPrefix for a discovered file from its path
+	// relative to the walked root. Defaults to the relative path with its
+	// extension stripped, which mirrors the input tree under the current
+	// directory.
+	OutPrefix func(relPath string) string
+}
+
+// FileResult is the outcome of splitting a single file discovered by
+// ProcessDir.
+type FileResult struct {
+	Path      string
+	OutPrefix string
+	Result    *Result
+	Err       error
+}
+
+// ProcessDir discovers *.log/*.txt files under root and splits each one
+// with its own Splitter, using up to opts.Parallel workers. Each file gets
+// its own OutputSink (and, for the default FileSink, its own common temp
+// file whenever one is used), so per-file failures are independent: an error splitting one file
+// is recorded on its FileResult rather than aborting the rest of the run.
+// The returned slice is ordered by path regardless of completion order.
+func ProcessDir(root string, opts BatchOptions) ([]FileResult, error) {
+	files, err := DiscoverFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+
+	derivePrefix := opts.OutPrefix
+	if derivePrefix == nil {
+		derivePrefix = func(relPath string) string {
+			ext := filepath.Ext(relPath)
+			return strings.TrimSuffix(relPath, ext)
+		}
+	}
+
+	jobs := make(chan int)
+	results := make([]FileResult, len(files))
+
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = splitOne(root, files[i], opts.Matcher, derivePrefix)
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, nil
+}
+
+func splitOne(root, path string, matcher TokenMatcher, derivePrefix func(string) string) FileResult {
+	relPath, err := filepath.Rel(root, path)
+	if err != nil {
+		relPath = path
+	}
+	outPrefix := derivePrefix(relPath)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return FileResult{Path: path, OutPrefix: outPrefix, Err: fmt.Errorf("opening %s: %w", path, err)}
+	}
+	defer f.Close()
+
+	splitter := New(Options{OutPrefix: outPrefix, Matcher: matcher})
+	result, err := splitter.Process(f)
+	return FileResult{Path: path, OutPrefix: outPrefix, Result: result, Err: err}
+}
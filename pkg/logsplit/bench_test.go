@@ -0,0 +1,102 @@
+package logsplit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// syntheticLog builds a log representative of the case ModeBuffered and
+// ModeTwoPass target: frequent common/banner lines with runs that only
+// start appearing well into the stream, so ModeStream's replay-on-open
+// copies a large and growing common buffer into every run it opens.
+func syntheticLog(commonLines, runs, linesPerRun int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < commonLines; i++ {
+		fmt.Fprintf(&buf, "banner line %d: system heartbeat ok\n", i)
+	}
+	for run := 0; run < runs; run++ {
+		for i := 0; i < linesPerRun; i++ {
+			fmt.Fprintf(&buf, "ptp4l[%d.000] ptp4l.%d.config: event %d\n", run*1000+i, run, i)
+		}
+		for i := 0; i < commonLines/runs; i++ {
+			fmt.Fprintf(&buf, "banner line %d: system heartbeat ok\n", commonLines+i)
+		}
+	}
+	return buf.Bytes()
+}
+
+func benchProcess(b *testing.B, mode Mode, data []byte, seekable bool) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		outPrefix := filepath.Join(b.TempDir(), "out")
+
+		var r io.Reader = bytes.NewReader(data)
+		if !seekable {
+			r = &onlyReader{bytes.NewReader(data)}
+		}
+
+		splitter := New(Options{OutPrefix: outPrefix, Mode: mode})
+		if _, err := splitter.Process(r); err != nil {
+			b.Fatalf("Process: %v", err)
+		}
+	}
+}
+
+// onlyReader hides bytes.Reader's Seek method so ModeAuto falls back to
+// ModeStream, letting the benchmark force non-seekable stream behavior.
+type onlyReader struct {
+	r *bytes.Reader
+}
+
+func (o *onlyReader) Read(p []byte) (int, error) { return o.r.Read(p) }
+
+// These syntheticLog parameters favor many runs each seeing a large, growing common
+// backlog before they open — the shape where ModeStream's O(runs *
+// commonBytes) replay copy dominates runtime.
+const (
+	syntheticCommonLines = 4000
+	syntheticRuns        = 200
+	syntheticLinesPerRun = 5
+)
+
+func BenchmarkProcessStream(b *testing.B) {
+	data := syntheticLog(syntheticCommonLines, syntheticRuns, syntheticLinesPerRun)
+	benchProcess(b, ModeStream, data, false)
+}
+
+func BenchmarkProcessBuffered(b *testing.B) {
+	data := syntheticLog(syntheticCommonLines, syntheticRuns, syntheticLinesPerRun)
+	benchProcess(b, ModeBuffered, data, false)
+}
+
+func BenchmarkProcessTwoPass(b *testing.B) {
+	data := syntheticLog(syntheticCommonLines, syntheticRuns, syntheticLinesPerRun)
+	benchProcess(b, ModeTwoPass, data, true)
+}
+
+// BenchmarkProcessAutoSeekable demonstrates ModeAuto transparently getting
+// ModeTwoPass's reduced I/O when handed a seekable input, such as the
+// *os.File the command-line tool passes for -input.
+func BenchmarkProcessAutoSeekable(b *testing.B) {
+	data := syntheticLog(syntheticCommonLines, syntheticRuns, syntheticLinesPerRun)
+	path := filepath.Join(b.TempDir(), "in.log")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		splitter := New(Options{OutPrefix: filepath.Join(b.TempDir(), "out")})
+		if _, err := splitter.Process(f); err != nil {
+			b.Fatalf("Process: %v", err)
+		}
+		f.Close()
+	}
+}
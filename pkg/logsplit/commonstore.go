@@ -0,0 +1,192 @@
+package logsplit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// commonStore buffers the lines a FileSink has written to its common
+// (tokenless) output, so that EnsureRun can seed a newly opened run with
+// everything seen so far. ModeStream and ModeBuffered each back an
+// OutputSink.WriteCommon call with a different commonStore.
+type commonStore interface {
+	io.Writer
+
+	// flush ensures every byte written so far is visible to newReader.
+	flush() error
+
+	// newReader returns a fresh reader over everything written so far.
+	// Callers must Close it.
+	newReader() (io.ReadCloser, error)
+
+	// promoteTo attempts to cheaply make path hold exactly the store's
+	// content (e.g. via rename) and reports whether it succeeded. Callers
+	// must fall back to newReader-then-copy when it returns false, nil.
+	promoteTo(path string) (bool, error)
+
+	// close releases any resources (open files, buffers) held by the store.
+	close() error
+}
+
+// fileCommonStore is the original ModeStream strategy: every common line is
+// appended to an on-disk temp file.
+type fileCommonStore struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func newFileCommonStore(path string) (*fileCommonStore, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create common temp file: %w", err)
+	}
+	return &fileCommonStore{path: path, file: f, writer: bufio.NewWriter(f)}, nil
+}
+
+func (s *fileCommonStore) Write(p []byte) (int, error) { return s.writer.Write(p) }
+func (s *fileCommonStore) flush() error                { return s.writer.Flush() }
+
+func (s *fileCommonStore) newReader() (io.ReadCloser, error) {
+	if err := s.writer.Flush(); err != nil {
+		return nil, err
+	}
+	return os.Open(s.path)
+}
+
+func (s *fileCommonStore) promoteTo(path string) (bool, error) {
+	if err := s.writer.Flush(); err != nil {
+		return false, err
+	}
+	s.file.Close()
+	if err := os.Rename(s.path, path); err != nil {
+		// Reopen so close() can still clean up path on the generic
+		// fallback the caller takes instead.
+		f, oerr := os.Open(s.path)
+		if oerr == nil {
+			s.file = f
+		}
+		return false, nil
+	}
+	s.file = nil
+	return true, nil
+}
+
+func (s *fileCommonStore) close() error {
+	if s.file != nil {
+		s.file.Close()
+	}
+	_ = os.Remove(s.path)
+	return nil
+}
+
+// bufferedCommonStore is the ModeBuffered strategy: common lines are kept
+// in memory up to maxBytes, with any overflow appended to a temp file at
+// spillPath.
+type bufferedCommonStore struct {
+	maxBytes  int64
+	buf       bytes.Buffer
+	spillPath string
+	spillFile *os.File
+	spillW    *bufio.Writer
+}
+
+func newBufferedCommonStore(spillPath string, maxBytes int64) *bufferedCommonStore {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxCommonBytes
+	}
+	return &bufferedCommonStore{maxBytes: maxBytes, spillPath: spillPath}
+}
+
+func (s *bufferedCommonStore) Write(p []byte) (int, error) {
+	total := len(p)
+	if s.spillFile == nil {
+		remaining := int(s.maxBytes) - s.buf.Len()
+		if remaining >= len(p) {
+			s.buf.Write(p)
+			return total, nil
+		}
+		if remaining > 0 {
+			s.buf.Write(p[:remaining])
+			p = p[remaining:]
+		}
+		f, err := os.Create(s.spillPath)
+		if err != nil {
+			return 0, fmt.Errorf("cannot create common overflow file: %w", err)
+		}
+		s.spillFile = f
+		s.spillW = bufio.NewWriter(f)
+	}
+	if len(p) > 0 {
+		if _, err := s.spillW.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+func (s *bufferedCommonStore) flush() error {
+	if s.spillW == nil {
+		return nil
+	}
+	return s.spillW.Flush()
+}
+
+func (s *bufferedCommonStore) newReader() (io.ReadCloser, error) {
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+	memReader := io.NopCloser(bytes.NewReader(s.buf.Bytes()))
+	if s.spillFile == nil {
+		return memReader, nil
+	}
+	spillReader, err := os.Open(s.spillPath)
+	if err != nil {
+		return nil, err
+	}
+	return &multiReadCloser{r: io.MultiReader(memReader, spillReader), c: spillReader}, nil
+}
+
+// ModeBuffered has no cheap rename path: its content spans an in-memory
+// buffer and (maybe) a spill file, so promotion always falls back to a copy.
+func (s *bufferedCommonStore) promoteTo(string) (bool, error) { return false, nil }
+
+func (s *bufferedCommonStore) close() error {
+	if s.spillFile != nil {
+		s.spillFile.Close()
+		_ = os.Remove(s.spillPath)
+	}
+	return nil
+}
+
+// nopCommonStore discards everything written to it. It backs ModeTwoPass
+// when Process's discovery pass found at least one run: every run (and, with
+// SplitByDaemon, every run/daemon pair) is then opened via EnsureRun/
+// EnsureRunDaemon before the writing pass begins, so FileSink.openSeeded
+// never needs to replay common lines into an output opened partway through
+// — there is nothing for a commonStore to remember. Process falls back to a
+// real store instead when discovery finds no runs, since Finalize's
+// run_unknown promotion then needs the common lines nopCommonStore would
+// otherwise have thrown away.
+type nopCommonStore struct{}
+
+func (nopCommonStore) Write(p []byte) (int, error) { return len(p), nil }
+func (nopCommonStore) flush() error                { return nil }
+func (nopCommonStore) newReader() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(nil)), nil
+}
+func (nopCommonStore) promoteTo(string) (bool, error) { return false, nil }
+func (nopCommonStore) close() error                   { return nil }
+
+// multiReadCloser pairs an io.Reader built from several sources with the
+// one of them that must be explicitly closed.
+type multiReadCloser struct {
+	r io.Reader
+	c io.Closer
+}
+
+func (m *multiReadCloser) Read(p []byte) (int, error) { return m.r.Read(p) }
+func (m *multiReadCloser) Close() error               { return m.c.Close() }
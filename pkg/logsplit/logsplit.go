@@ -0,0 +1,286 @@
+// Package logsplit implements the run-splitting behavior behind the
+// linuxptp-daemon-log-splitter tool: scanning a combined PTP daemon log for
+// run tokens like "ptp4l.N.config" and routing each line to its run's
+// output, while replaying tokenless (common) lines into every run.
+package logsplit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Options configures a Splitter.
+type Options struct {
+	// OutPrefix names the outputs the default FileSink produces, as
+	// "<OutPrefix>.run_<id>.log". Required unless Sink is set.
+	OutPrefix string
+
+	// Matcher identifies run tokens in each line. Defaults to a
+	// NewRegexTokenMatcher(DefaultRunTokenPattern) matcher.
+	Matcher TokenMatcher
+
+	// Sink receives classified lines. Defaults to a NewFileSink(OutPrefix).
+	Sink OutputSink
+
+	// Mode selects the default FileSink's common-line strategy and, for
+	// ModeTwoPass (or ModeAuto with an io.ReadSeeker input), whether Process
+	// makes a discovery pass before writing anything. Defaults to ModeAuto.
+	// When Sink is set, only the choice of passes applies.
+	Mode Mode
+
+	// MaxCommonBytes caps how much of the common prefix ModeBuffered
+	// keeps in memory before spilling the remainder to a temp file.
+	// Defaults to 4 MiB when zero. Ignored outside ModeBuffered.
+	MaxCommonBytes int64
+
+	// Shards splits a single pass over the input across multiple
+	// cooperating Splitter instances, each invoked with the same Shards
+	// but a distinct Shard index in [0, Shards). A run is opened and
+	// written only by the instance for which ShardIndex(run, Shards) ==
+	// Shard; common (tokenless) lines are written by every instance
+	// regardless, so downstream tooling must combine all shards' outputs
+	// to reconstruct the full log. Shards <= 1 disables sharding: every
+	// run is processed, as if only one instance existed.
+	Shards int
+
+	// Shard is this instance's index when Shards > 1. Ignored otherwise.
+	Shard int
+
+	// SplitByDaemon additionally routes each matched line to a
+	// per-(run, daemon) output via the sink's EnsureRunDaemon/
+	// WriteRunDaemon, for tokens whose Matcher captured a daemon name.
+	SplitByDaemon bool
+}
+
+// Result summarizes a single Process call.
+type Result struct {
+	// RunsFound lists the distinct run identifiers seen, in the order
+	// they were first encountered.
+	RunsFound []string
+
+	// Runs holds manifest-style stats for each run in RunsFound, in the
+	// same order.
+	Runs []RunInfo
+}
+
+// AnyRunFound reports whether any run token was seen during Process.
+func (r *Result) AnyRunFound() bool {
+	return len(r.RunsFound) > 0
+}
+
+// Splitter splits a combined log into per-run outputs via an OutputSink.
+type Splitter struct {
+	opts Options
+}
+
+// New returns a Splitter configured by opts. Opening the default sink is
+// deferred to Process, since it may fail and New cannot return an error.
+func New(opts Options) *Splitter {
+	return &Splitter{opts: opts}
+}
+
+// Process reads every line from r, classifies it with the configured
+// TokenMatcher, and writes it to the configured OutputSink. It closes the
+// sink before returning, whether or not an error occurred.
+//
+// When Options.Mode is ModeTwoPass, or is ModeAuto and r implements
+// io.ReadSeeker, Process first scans r to discover every run and opens them
+// all before making a second, writing pass; this avoids ever having to
+// replay buffered common lines into a run opened partway through the
+// input. Requesting ModeTwoPass for a non-seekable r is an error.
+func (s *Splitter) Process(r io.Reader) (*Result, error) {
+	matcher := s.opts.Matcher
+	if matcher == nil {
+		matcher = NewRegexTokenMatcher(DefaultRunTokenPattern)
+	}
+
+	sharded := s.opts.Shards > 1
+
+	if s.opts.Sink != nil {
+		defer s.opts.Sink.Close()
+	}
+
+	seeker, isSeeker := r.(io.ReadSeeker)
+	twoPass := s.opts.Mode == ModeTwoPass || (s.opts.Mode == ModeAuto && isSeeker)
+
+	var discoveredRuns []string
+	var discoveredRunDaemons [][2]string
+	if twoPass {
+		if !isSeeker {
+			return nil, fmt.Errorf("logsplit: ModeTwoPass requires an io.ReadSeeker input")
+		}
+		var err error
+		discoveredRuns, discoveredRunDaemons, err = discoverRuns(seeker, matcher, sharded, s.opts.Shards, s.opts.Shard, s.opts.SplitByDaemon)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("seeking back to start for two-pass mode: %w", err)
+		}
+	}
+
+	sink := s.opts.Sink
+	if sink == nil {
+		sinkMode := s.opts.Mode
+		if twoPass {
+			sinkMode = ModeTwoPass
+		}
+		if twoPass && len(discoveredRuns) == 0 {
+			// No run will be pre-opened, so a nopCommonStore would have
+			// nothing to fall back to if Finalize later needs to promote
+			// the common lines to run_unknown. Fall back to a real store.
+			sinkMode = ModeStream
+		}
+		fileSink, err := NewFileSinkMode(s.opts.OutPrefix, sinkMode, s.opts.MaxCommonBytes)
+		if err != nil {
+			return nil, err
+		}
+		sink = fileSink
+		defer sink.Close()
+	}
+
+	if twoPass {
+		for _, run := range discoveredRuns {
+			if _, err := sink.EnsureRun(run); err != nil {
+				return nil, fmt.Errorf("opening run file for %s: %w", run, err)
+			}
+		}
+		for _, pair := range discoveredRunDaemons {
+			if _, err := sink.EnsureRunDaemon(pair[0], pair[1]); err != nil {
+				return nil, fmt.Errorf("opening run/daemon file for %s/%s: %w", pair[0], pair[1], err)
+			}
+		}
+	}
+
+	result := &Result{}
+	builders := make(map[string]*runBuilder)
+	var openRuns []string
+
+	// common tracks the running totals of tokenless lines, used to seed
+	// the manifest stats of a run the moment it is opened, mirroring the
+	// sink's own common-buffer replay.
+	common := newRunBuilder("", RunInfo{})
+
+	err := forEachLine(r, func(line string) error {
+		ts := extractTimestamp(line)
+
+		tokens := matcher.Match(line)
+		if len(tokens) == 0 {
+			if werr := sink.WriteCommon(line); werr != nil {
+				return werr
+			}
+			common.addLine(line, ts, "")
+			for _, run := range openRuns {
+				builders[run].addLine(line, ts, "")
+			}
+			return nil
+		}
+
+		for _, tok := range tokens {
+			if sharded && ShardIndex(tok.Run, s.opts.Shards) != s.opts.Shard {
+				continue
+			}
+			b, ok := builders[tok.Run]
+			if !ok {
+				b = newRunBuilder(tok.Run, common.info)
+				builders[tok.Run] = b
+				openRuns = append(openRuns, tok.Run)
+				result.RunsFound = append(result.RunsFound, tok.Run)
+			}
+			if _, oerr := sink.EnsureRun(tok.Run); oerr != nil {
+				return fmt.Errorf("opening run file for %s: %w", tok.Run, oerr)
+			}
+			if werr := sink.WriteRun(tok.Run, line); werr != nil {
+				return werr
+			}
+			b.addLine(line, ts, tok.Daemon)
+
+			if s.opts.SplitByDaemon && tok.Daemon != "" {
+				if _, derr := sink.EnsureRunDaemon(tok.Run, tok.Daemon); derr != nil {
+					return fmt.Errorf("opening run/daemon file for %s/%s: %w", tok.Run, tok.Daemon, derr)
+				}
+				if werr := sink.WriteRunDaemon(tok.Run, tok.Daemon, line); werr != nil {
+					return werr
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, run := range result.RunsFound {
+		result.Runs = append(result.Runs, builders[run].build())
+	}
+
+	// Under sharding, a shard that was assigned no runs must produce no
+	// output of its own rather than falling back to a run_unknown file:
+	// result.AnyRunFound() is gated by this shard's predicate, so it being
+	// false here means only that this shard is empty, not that the whole
+	// input had no run tokens.
+	if err := sink.Finalize(result.AnyRunFound(), sharded); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// forEachLine calls fn with each '\n'-terminated line of r, adding a
+// trailing newline to a final unterminated line.
+func forEachLine(r io.Reader, fn func(line string) error) error {
+	bufReader := bufio.NewReader(r)
+	for {
+		line, err := bufReader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("reading input: %w", err)
+		}
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+		if len(line) == 0 || line[len(line)-1] != '\n' {
+			line += "\n"
+		}
+		if ferr := fn(line); ferr != nil {
+			return ferr
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return nil
+}
+
+// discoverRuns scans r once, without writing anything, to find every run
+// this shard is responsible for, in order of first appearance. When
+// wantDaemons is set, it also returns every distinct (run, daemon) pair seen,
+// so a two-pass Splitter can pre-open per-daemon outputs the same way it
+// pre-opens run outputs, and a common store that skips buffering (such as
+// nopCommonStore) still seeds every output correctly.
+func discoverRuns(r io.Reader, matcher TokenMatcher, sharded bool, shards, shard int, wantDaemons bool) ([]string, [][2]string, error) {
+	seenRuns := make(map[string]struct{})
+	var runs []string
+	seenPairs := make(map[string]struct{})
+	var runDaemons [][2]string
+	err := forEachLine(r, func(line string) error {
+		for _, tok := range matcher.Match(line) {
+			if sharded && ShardIndex(tok.Run, shards) != shard {
+				continue
+			}
+			if _, ok := seenRuns[tok.Run]; !ok {
+				seenRuns[tok.Run] = struct{}{}
+				runs = append(runs, tok.Run)
+			}
+			if wantDaemons && tok.Daemon != "" {
+				key := runDaemonKey(tok.Run, tok.Daemon)
+				if _, ok := seenPairs[key]; !ok {
+					seenPairs[key] = struct{}{}
+					runDaemons = append(runDaemons, [2]string{tok.Run, tok.Daemon})
+				}
+			}
+		}
+		return nil
+	})
+	return runs, runDaemons, err
+}
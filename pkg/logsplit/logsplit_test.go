@@ -0,0 +1,159 @@
+package logsplit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sampleLog() string {
+	var b strings.Builder
+	b.WriteString("banner: system heartbeat ok\n")
+	b.WriteString("ptp4l[1.000] ptp4l.0.config: event a\n")
+	b.WriteString("banner: system heartbeat ok\n")
+	b.WriteString("ptp4l[2.000] ptp4l.1.config: event b\n")
+	b.WriteString("phc2sys[3.000] phc2sys.1.config: event c\n")
+	b.WriteString("banner: system heartbeat ok\n")
+	return b.String()
+}
+
+// outputsByName runs a Splitter against data through the default FileSink
+// and returns every output file it produced, keyed by the suffix FileSink
+// appended to outPrefix (e.g. "run_0.log", "run_0.ptp4l.log", or
+// "run_unknown.log"), so callers can compare across modes without needing to
+// know in advance which outputs a given input and options will produce.
+func outputsByName(t *testing.T, data []byte, mode Mode, splitByDaemon bool) map[string][]byte {
+	t.Helper()
+	dir := t.TempDir()
+	outPrefix := filepath.Join(dir, "out")
+	splitter := New(Options{OutPrefix: outPrefix, Mode: mode, SplitByDaemon: splitByDaemon})
+	if _, err := splitter.Process(bytes.NewReader(data)); err != nil {
+		t.Fatalf("mode %d: Process: %v", mode, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("mode %d: ReadDir: %v", mode, err)
+	}
+	got := make(map[string][]byte)
+	for _, entry := range entries {
+		name := strings.TrimPrefix(entry.Name(), "out.")
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("mode %d: reading %s: %v", mode, entry.Name(), err)
+		}
+		got[name] = content
+	}
+	return got
+}
+
+// TestProcessModesAgree checks that ModeStream, ModeBuffered, and
+// ModeTwoPass produce byte-identical outputs for the same input, despite
+// storing common lines completely differently. It covers the ordinary case
+// (runs present), a tokenless log (which must fall back to run_unknown
+// under every mode), and -split-by-daemon (whose per-daemon files must
+// still carry their seeded common prefix under every mode).
+func TestProcessModesAgree(t *testing.T) {
+	cases := []struct {
+		name          string
+		data          []byte
+		splitByDaemon bool
+	}{
+		{name: "runs present", data: []byte(sampleLog())},
+		{name: "no run tokens", data: []byte("banner: system heartbeat ok\nbanner: another common line\n")},
+		{name: "split by daemon", data: []byte(sampleLog()), splitByDaemon: true},
+	}
+	modes := []Mode{ModeStream, ModeBuffered, ModeTwoPass}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var reference map[string][]byte
+			for _, mode := range modes {
+				got := outputsByName(t, tc.data, mode, tc.splitByDaemon)
+				if reference == nil {
+					reference = got
+					continue
+				}
+				if len(got) != len(reference) {
+					t.Fatalf("mode %d: got %d output files, want %d (got %v, want %v)", mode, len(got), len(reference), names(got), names(reference))
+				}
+				for name, content := range reference {
+					if !bytes.Equal(got[name], content) {
+						t.Errorf("mode %d: output %q differs from ModeStream's:\n got:  %q\n want: %q", mode, name, got[name], content)
+					}
+				}
+			}
+		})
+	}
+}
+
+func names(m map[string][]byte) []string {
+	var ns []string
+	for n := range m {
+		ns = append(ns, n)
+	}
+	return ns
+}
+
+// TestProcessRunUnknownFallback checks that an input with no run tokens is
+// written entirely to the run_unknown fallback, and only then.
+func TestProcessRunUnknownFallback(t *testing.T) {
+	data := "banner: system heartbeat ok\nbanner: another common line\n"
+
+	sink := NewMemSink()
+	splitter := New(Options{Sink: sink})
+	result, err := splitter.Process(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if result.AnyRunFound() {
+		t.Fatalf("AnyRunFound() = true, want false for a log with no run tokens")
+	}
+	if !sink.UnknownWritten() {
+		t.Fatalf("UnknownWritten() = false, want true")
+	}
+	if got := strings.Join(sink.Unknown(), ""); got != data {
+		t.Errorf("Unknown() = %q, want %q", got, data)
+	}
+}
+
+// TestProcessShardingSuppressesEmptyShardFallback checks that sharding
+// routes each run to exactly one shard, that every shard still sees every
+// common line, and that a shard assigned no runs produces no run_unknown
+// fallback of its own.
+func TestProcessShardingSuppressesEmptyShardFallback(t *testing.T) {
+	data := []byte(sampleLog())
+	const shards = 4
+
+	runOwner := make(map[string]int)
+	var commonByShard [][]string
+	for shard := 0; shard < shards; shard++ {
+		sink := NewMemSink()
+		splitter := New(Options{Sink: sink, Shards: shards, Shard: shard})
+		result, err := splitter.Process(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("shard %d: Process: %v", shard, err)
+		}
+		for _, run := range result.RunsFound {
+			if prior, ok := runOwner[run]; ok {
+				t.Fatalf("run %s claimed by both shard %d and shard %d", run, prior, shard)
+			}
+			runOwner[run] = shard
+		}
+		if !result.AnyRunFound() && sink.UnknownWritten() {
+			t.Errorf("shard %d: found no runs but still wrote a run_unknown fallback", shard)
+		}
+		commonByShard = append(commonByShard, sink.Common())
+	}
+
+	if len(runOwner) != 2 {
+		t.Fatalf("got %d distinct runs assigned across shards, want 2 (ptp4l.0 and ptp4l.1)", len(runOwner))
+	}
+	for shard := 1; shard < shards; shard++ {
+		if strings.Join(commonByShard[shard], "") != strings.Join(commonByShard[0], "") {
+			t.Errorf("shard %d saw different common lines than shard 0, want every shard to replay the full common stream", shard)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package logsplit
+
+import (
+	"regexp"
+	"sort"
+)
+
+// logTimestampPattern extracts a linuxptp-style bracketed monotonic
+// timestamp, e.g. the "527667.399" in "ptp4l[527667.399]: ...".
+var logTimestampPattern = regexp.MustCompile(`\[\s*([0-9]+\.[0-9]+)\s*\]`)
+
+// extractTimestamp returns the first bracketed timestamp in line, or "" if
+// line has none.
+func extractTimestamp(line string) string {
+	m := logTimestampPattern.FindStringSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// RunInfo summarizes one run's output for -manifest: how much was written
+// to it, the span of timestamps seen in its lines, and which daemons
+// contributed to it.
+type RunInfo struct {
+	Run       string   `json:"run"`
+	Bytes     int64    `json:"bytes"`
+	Lines     int64    `json:"lines"`
+	FirstTime string   `json:"first_time,omitempty"`
+	LastTime  string   `json:"last_time,omitempty"`
+	Daemons   []string `json:"daemons,omitempty"`
+}
+
+// runBuilder accumulates a RunInfo while a Splitter streams lines to it.
+type runBuilder struct {
+	info    RunInfo
+	daemons map[string]struct{}
+}
+
+func newRunBuilder(run string, seed RunInfo) *runBuilder {
+	seed.Run = run
+	return &runBuilder{info: seed, daemons: make(map[string]struct{})}
+}
+
+func (b *runBuilder) addLine(line string, ts string, daemon string) {
+	b.info.Bytes += int64(len(line))
+	b.info.Lines++
+	if ts != "" {
+		if b.info.FirstTime == "" {
+			b.info.FirstTime = ts
+		}
+		b.info.LastTime = ts
+	}
+	if daemon != "" {
+		b.daemons[daemon] = struct{}{}
+	}
+}
+
+func (b *runBuilder) build() RunInfo {
+	info := b.info
+	if len(b.daemons) > 0 {
+		info.Daemons = make([]string, 0, len(b.daemons))
+		for d := range b.daemons {
+			info.Daemons = append(info.Daemons, d)
+		}
+		sort.Strings(info.Daemons)
+	}
+	return info
+}
@@ -0,0 +1,83 @@
+package logsplit
+
+import "regexp"
+
+// DefaultRunTokenPattern matches tokens like "ptp4l.N.config" or
+// "phc2sys.N.config", capturing the daemon name in the "daemon" group and
+// the run identifier N in the "run" group.
+const DefaultRunTokenPattern = `\b(?P<daemon>[A-Za-z0-9_-]+)\.(?P<run>\d+)\.config\b`
+
+// Token is a single run token found in a line: the run identifier it
+// belongs to and, when the pattern captures one, the daemon name prefix
+// (e.g. "ptp4l" in "ptp4l.3.config").
+type Token struct {
+	Run    string
+	Daemon string
+}
+
+// TokenMatcher identifies the run tokens referenced by a single line of log
+// output. Implementations must be safe for repeated calls from a single
+// goroutine; a Splitter does not call a TokenMatcher concurrently, though a
+// Matcher shared across ProcessDir's workers must tolerate concurrent use.
+type TokenMatcher interface {
+	// Match returns every run token found in line, de-duplicated by run
+	// identifier, in no particular order. A nil or empty result means the
+	// line has no run token and should be treated as common/global.
+	Match(line string) []Token
+}
+
+// regexTokenMatcher is the default TokenMatcher, backed by a single regular
+// expression with a required "run" named capture group and an optional
+// "daemon" named capture group.
+type regexTokenMatcher struct {
+	re        *regexp.Regexp
+	runIdx    int
+	daemonIdx int
+}
+
+// NewRegexTokenMatcher builds a TokenMatcher from a regular expression with
+// a named capture group "run" (and, optionally, "daemon"). It panics if
+// pattern does not compile or has no "run" group, matching the
+// fail-on-construction behavior of regexp.MustCompile.
+func NewRegexTokenMatcher(pattern string) TokenMatcher {
+	re := regexp.MustCompile(pattern)
+	runIdx := -1
+	daemonIdx := -1
+	for i, name := range re.SubexpNames() {
+		switch name {
+		case "run":
+			runIdx = i
+		case "daemon":
+			daemonIdx = i
+		}
+	}
+	if runIdx == -1 {
+		panic("logsplit: pattern must have a named \"run\" capture group: " + pattern)
+	}
+	return &regexTokenMatcher{re: re, runIdx: runIdx, daemonIdx: daemonIdx}
+}
+
+func (m *regexTokenMatcher) Match(line string) []Token {
+	matches := m.re.FindAllStringSubmatch(line, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(matches))
+	var tokens []Token
+	for _, match := range matches {
+		run := match[m.runIdx]
+		if run == "" {
+			continue
+		}
+		if _, ok := seen[run]; ok {
+			continue
+		}
+		seen[run] = struct{}{}
+		var daemon string
+		if m.daemonIdx != -1 {
+			daemon = match[m.daemonIdx]
+		}
+		tokens = append(tokens, Token{Run: run, Daemon: daemon})
+	}
+	return tokens
+}
@@ -0,0 +1,91 @@
+package logsplit
+
+// MemSink is an in-memory OutputSink. It mirrors FileSink's seeding and
+// fallback behavior without touching the filesystem, so callers (tests in
+// particular) can assert on a Splitter's output directly.
+type MemSink struct {
+	common         []string
+	runs           map[string][]string
+	runDaemons     map[string][]string
+	unknown        []string
+	unknownWritten bool
+}
+
+// NewMemSink returns an empty MemSink.
+func NewMemSink() *MemSink {
+	return &MemSink{
+		runs:       make(map[string][]string),
+		runDaemons: make(map[string][]string),
+	}
+}
+
+func (s *MemSink) WriteCommon(line string) error {
+	s.common = append(s.common, line)
+	for run, lines := range s.runs {
+		s.runs[run] = append(lines, line)
+	}
+	for key, lines := range s.runDaemons {
+		s.runDaemons[key] = append(lines, line)
+	}
+	return nil
+}
+
+func (s *MemSink) EnsureRun(run string) (bool, error) {
+	if _, ok := s.runs[run]; ok {
+		return false, nil
+	}
+	s.runs[run] = append([]string(nil), s.common...)
+	return true, nil
+}
+
+func (s *MemSink) WriteRun(run, line string) error {
+	s.runs[run] = append(s.runs[run], line)
+	return nil
+}
+
+func (s *MemSink) EnsureRunDaemon(run, daemon string) (bool, error) {
+	key := runDaemonKey(run, daemon)
+	if _, ok := s.runDaemons[key]; ok {
+		return false, nil
+	}
+	s.runDaemons[key] = append([]string(nil), s.common...)
+	return true, nil
+}
+
+func (s *MemSink) WriteRunDaemon(run, daemon, line string) error {
+	key := runDaemonKey(run, daemon)
+	s.runDaemons[key] = append(s.runDaemons[key], line)
+	return nil
+}
+
+func (s *MemSink) Finalize(anyRunFound, suppressFallback bool) error {
+	if anyRunFound || suppressFallback {
+		return nil
+	}
+	s.unknown = append([]string(nil), s.common...)
+	s.unknownWritten = true
+	return nil
+}
+
+func (s *MemSink) Close() error { return nil }
+
+// Common returns the tokenless lines written so far.
+func (s *MemSink) Common() []string { return s.common }
+
+// Run returns the lines written to run, or nil if it was never opened.
+func (s *MemSink) Run(run string) []string { return s.runs[run] }
+
+// RunDaemon returns the lines written to the (run, daemon) pair, or nil if
+// it was never opened.
+func (s *MemSink) RunDaemon(run, daemon string) []string {
+	return s.runDaemons[runDaemonKey(run, daemon)]
+}
+
+// Unknown returns the run_unknown fallback lines, or nil if Finalize never
+// promoted the common lines to it. Use UnknownWritten to distinguish that
+// from a genuine but empty fallback.
+func (s *MemSink) Unknown() []string { return s.unknown }
+
+// UnknownWritten reports whether Finalize promoted the common lines to the
+// run_unknown fallback.
+func (s *MemSink) UnknownWritten() bool { return s.unknownWritten }
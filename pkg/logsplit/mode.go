@@ -0,0 +1,35 @@
+package logsplit
+
+// Mode selects how a Splitter (via the default FileSink) stores tokenless
+// (common) lines while it waits to learn about new runs, and how it
+// schedules its pass(es) over the input.
+type Mode int
+
+const (
+	// ModeAuto picks ModeTwoPass when Process is given an io.ReadSeeker,
+	// and ModeStream otherwise.
+	ModeAuto Mode = iota
+
+	// ModeStream buffers common lines in a temp file and replays it into
+	// each run the moment the run is first seen. This is the original,
+	// always-available strategy; it costs O(runs * commonBytes) I/O when
+	// runs appear late in a log with frequent common lines.
+	ModeStream
+
+	// ModeBuffered keeps common lines in memory up to MaxCommonBytes,
+	// spilling the overflow to a temp file, avoiding a syscall per common
+	// line for logs whose common prefix fits comfortably in memory.
+	ModeBuffered
+
+	// ModeTwoPass requires a seekable input. It scans once to discover
+	// every run, opens them all up front, then scans again writing
+	// directly to each run's output. Because every run is already open
+	// before the second pass writes its first byte, no common-line
+	// replay copy is ever needed. Requesting it for a non-seekable input
+	// is an error.
+	ModeTwoPass
+)
+
+// defaultMaxCommonBytes is the in-memory cap ModeBuffered uses when Options
+// does not set MaxCommonBytes.
+const defaultMaxCommonBytes = 4 << 20 // 4 MiB
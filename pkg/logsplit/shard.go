@@ -0,0 +1,12 @@
+package logsplit
+
+import "hash/fnv"
+
+// ShardIndex returns which shard, of shards total, run falls into. It
+// hashes run with FNV-1a so that independent processes invoked with the
+// same Shards value agree on the assignment without communicating.
+func ShardIndex(run string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(run))
+	return int(h.Sum32() % uint32(shards))
+}
@@ -0,0 +1,269 @@
+package logsplit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// OutputSink receives the lines a Splitter has classified and is
+// responsible for routing them to their final destination (files, an
+// in-memory buffer, etc). A Splitter drives a sink from a single goroutine
+// in stream order.
+type OutputSink interface {
+	// WriteCommon appends a line that carried no run token. The sink must
+	// also deliver it to every run (and run/daemon pair) already opened
+	// via EnsureRun/EnsureRunDaemon, since those outputs need every
+	// common line that arrives after they were opened in addition to the
+	// ones seeded at open time.
+	WriteCommon(line string) error
+
+	// EnsureRun opens the named run if it has not been seen before,
+	// seeding its output with every common line written so far, and
+	// reports whether this call newly opened it.
+	EnsureRun(run string) (isNew bool, err error)
+
+	// WriteRun appends a line to a run previously opened via EnsureRun.
+	WriteRun(run, line string) error
+
+	// EnsureRunDaemon opens the per-daemon output for (run, daemon) if it
+	// has not been seen before, seeding it like EnsureRun, for callers
+	// using -split-by-daemon. It reports whether this call newly opened
+	// it.
+	EnsureRunDaemon(run, daemon string) (isNew bool, err error)
+
+	// WriteRunDaemon appends a line to a (run, daemon) output previously
+	// opened via EnsureRunDaemon.
+	WriteRunDaemon(run, daemon, line string) error
+
+	// Finalize is called exactly once after the input has been fully
+	// consumed. anyRunFound reports whether any run token was seen during
+	// the whole pass; when false, implementations typically promote the
+	// common output to a single fallback file. suppressFallback disables
+	// that promotion even when anyRunFound is false — set by the Splitter
+	// when sharding is enabled, since an empty shard simply had no runs
+	// assigned to it rather than the input having none at all, and must
+	// produce no output of its own.
+	Finalize(anyRunFound, suppressFallback bool) error
+
+	// Close releases any resources (open files, buffers) held by the
+	// sink. It is safe to call after Finalize.
+	Close() error
+}
+
+// FileSink is the default OutputSink. It writes the common (tokenless)
+// lines to a commonStore (an on-disk temp file for ModeStream, or an
+// in-memory buffer for ModeBuffered) and lazily creates one
+// "<outPrefix>.run_<id>.log" file per run (and, for -split-by-daemon,
+// "<outPrefix>.run_<id>.<daemon>.log" per run/daemon pair), seeding each
+// newly opened output with the common store's contents so far.
+type FileSink struct {
+	outPrefix string
+	common    commonStore
+
+	runs       map[string]*fileSinkRun
+	runDaemons map[string]*fileSinkRun
+}
+
+type fileSinkRun struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// NewFileSink creates a FileSink that writes outputs alongside outPrefix,
+// using ModeStream to store common lines. If outPrefix names a nested path
+// (as directory-batch callers do), the parent directory is created as
+// needed.
+func NewFileSink(outPrefix string) (*FileSink, error) {
+	return NewFileSinkMode(outPrefix, ModeStream, 0)
+}
+
+// NewFileSinkMode creates a FileSink like NewFileSink, but lets the caller
+// pick how common lines are stored: ModeStream (the default) spools them to
+// a temp file, ModeBuffered keeps up to maxCommonBytes of them in memory,
+// spilling any overflow to a temp file, and ModeTwoPass uses no storage at
+// all, since a two-pass Splitter opens every run via EnsureRun before
+// writing a single line and so never needs to replay common lines into a
+// run opened partway through. ModeAuto behaves as ModeStream here, since
+// FileSink's storage choice is orthogonal to whether a Splitter makes one or
+// two passes over the input; callers wanting ModeTwoPass's no-temp-file
+// behavior must request it explicitly.
+func NewFileSinkMode(outPrefix string, mode Mode, maxCommonBytes int64) (*FileSink, error) {
+	if dir := filepath.Dir(outPrefix); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("cannot create output directory %s: %w", dir, err)
+		}
+	}
+
+	var common commonStore
+	var err error
+	switch mode {
+	case ModeBuffered:
+		common = newBufferedCommonStore(fmt.Sprintf("%s.common.overflow.tmp", outPrefix), maxCommonBytes)
+	case ModeTwoPass:
+		common = nopCommonStore{}
+	default:
+		common, err = newFileCommonStore(fmt.Sprintf("%s.common.tmp", outPrefix))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{
+		outPrefix:  outPrefix,
+		common:     common,
+		runs:       make(map[string]*fileSinkRun),
+		runDaemons: make(map[string]*fileSinkRun),
+	}, nil
+}
+
+// runDaemonKey returns the map key FileSink uses for a (run, daemon) pair.
+func runDaemonKey(run, daemon string) string {
+	return run + "\x00" + daemon
+}
+
+func (s *FileSink) WriteCommon(line string) error {
+	if _, err := io.WriteString(s.common, line); err != nil {
+		return fmt.Errorf("writing common store: %w", err)
+	}
+	for run, r := range s.runs {
+		if _, err := r.writer.WriteString(line); err != nil {
+			return fmt.Errorf("writing run file for %s: %w", run, err)
+		}
+	}
+	for key, r := range s.runDaemons {
+		if _, err := r.writer.WriteString(line); err != nil {
+			return fmt.Errorf("writing run/daemon file for %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// openSeeded creates path and seeds it with the common store's contents so
+// far, the shared logic behind EnsureRun and EnsureRunDaemon.
+func (s *FileSink) openSeeded(path string) (*fileSinkRun, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	r, err := s.common.newReader()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	_, cErr := io.Copy(w, r)
+	r.Close()
+	if cErr != nil {
+		w.Flush()
+		f.Close()
+		return nil, cErr
+	}
+	return &fileSinkRun{file: f, writer: w}, nil
+}
+
+func (s *FileSink) EnsureRun(run string) (bool, error) {
+	if _, ok := s.runs[run]; ok {
+		return false, nil
+	}
+	ro, err := s.openSeeded(fmt.Sprintf("%s.run_%s.log", s.outPrefix, run))
+	if err != nil {
+		return false, err
+	}
+	s.runs[run] = ro
+	return true, nil
+}
+
+func (s *FileSink) WriteRun(run, line string) error {
+	r, ok := s.runs[run]
+	if !ok {
+		return fmt.Errorf("run %s was not opened via EnsureRun", run)
+	}
+	if _, err := r.writer.WriteString(line); err != nil {
+		return fmt.Errorf("writing run file for %s: %w", run, err)
+	}
+	return nil
+}
+
+func (s *FileSink) EnsureRunDaemon(run, daemon string) (bool, error) {
+	key := runDaemonKey(run, daemon)
+	if _, ok := s.runDaemons[key]; ok {
+		return false, nil
+	}
+	ro, err := s.openSeeded(fmt.Sprintf("%s.run_%s.%s.log", s.outPrefix, run, daemon))
+	if err != nil {
+		return false, err
+	}
+	s.runDaemons[key] = ro
+	return true, nil
+}
+
+func (s *FileSink) WriteRunDaemon(run, daemon, line string) error {
+	key := runDaemonKey(run, daemon)
+	r, ok := s.runDaemons[key]
+	if !ok {
+		return fmt.Errorf("run/daemon %s/%s was not opened via EnsureRunDaemon", run, daemon)
+	}
+	if _, err := r.writer.WriteString(line); err != nil {
+		return fmt.Errorf("writing run/daemon file for %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *FileSink) Finalize(anyRunFound, suppressFallback bool) error {
+	if err := s.common.flush(); err != nil {
+		return fmt.Errorf("flushing common store: %w", err)
+	}
+	for run, r := range s.runs {
+		if err := r.writer.Flush(); err != nil {
+			return fmt.Errorf("flushing run file for %s: %w", run, err)
+		}
+	}
+	for key, r := range s.runDaemons {
+		if err := r.writer.Flush(); err != nil {
+			return fmt.Errorf("flushing run/daemon file for %s: %w", key, err)
+		}
+	}
+
+	if anyRunFound || suppressFallback {
+		return nil
+	}
+
+	unknownPath := fmt.Sprintf("%s.run_unknown.log", s.outPrefix)
+	if ok, err := s.common.promoteTo(unknownPath); err != nil {
+		return err
+	} else if ok {
+		return nil
+	}
+
+	r, err := s.common.newReader()
+	if err != nil {
+		return fmt.Errorf("opening common store for copy: %w", err)
+	}
+	defer r.Close()
+	wf, err := os.Create(unknownPath)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", unknownPath, err)
+	}
+	defer wf.Close()
+	if _, err := io.Copy(wf, r); err != nil {
+		return fmt.Errorf("copying to %s: %w", unknownPath, err)
+	}
+	return nil
+}
+
+func (s *FileSink) Close() error {
+	for run, r := range s.runs {
+		if err := r.file.Close(); err != nil {
+			return fmt.Errorf("closing run file for %s: %w", run, err)
+		}
+	}
+	for key, r := range s.runDaemons {
+		if err := r.file.Close(); err != nil {
+			return fmt.Errorf("closing run/daemon file for %s: %w", key, err)
+		}
+	}
+	return s.common.close()
+}